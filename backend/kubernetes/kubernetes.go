@@ -0,0 +1,199 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build kubernetes
+
+// Package kubernetes implements vfs.SnapshotBackend on top of the Kubernetes
+// API server: each sealed log file becomes a Secret, and the single-writer
+// lock is a Lease object, the same primitive Terraform's kubernetes backend
+// uses to serialize state writes. It's kept behind the "kubernetes" build
+// tag so that core installs don't pull in client-go.
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/auula/momentdb/vfs"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	leaseName    = "momentdb-snapshot-lock"
+	leaseHolder  = "momentdb"
+	leaseSeconds = 30
+	labelSegment = "momentdb.io/segment"
+)
+
+// Backend stores sealed log files as Secret objects in a namespace and uses
+// a Lease for single-writer locking, mirroring how Terraform's kubernetes
+// backend serializes state writes with leases.
+type Backend struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// New creates a Backend that stores segments in namespace.
+func New(client kubernetes.Interface, namespace string) *Backend {
+	return &Backend{client: client, namespace: namespace}
+}
+
+func secretName(id string) string {
+	return "momentdb-segment-" + strings.ReplaceAll(id, ".", "-")
+}
+
+func (b *Backend) PutSegment(ctx context.Context, id string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(id),
+			Namespace: b.namespace,
+			Labels:    map[string]string{labelSegment: id},
+		},
+		Data: map[string][]byte{"segment": data},
+	}
+
+	secrets := b.client.CoreV1().Secrets(b.namespace)
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+func (b *Backend) GetSegment(ctx context.Context, id string) (io.ReadCloser, error) {
+	secret, err := b.client.CoreV1().Secrets(b.namespace).Get(ctx, secretName(id), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(string(secret.Data["segment"]))), nil
+}
+
+func (b *Backend) ListSince(ctx context.Context, lsn uint64) ([]vfs.SegmentRef, error) {
+	list, err := b.client.CoreV1().Secrets(b.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSegment,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []vfs.SegmentRef
+	for _, secret := range list.Items {
+		id := secret.Labels[labelSegment]
+		segmentLSN, err := parseLSN(id)
+		if err != nil {
+			continue
+		}
+		if segmentLSN > lsn {
+			refs = append(refs, vfs.SegmentRef{ID: id, LSN: segmentLSN})
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].LSN < refs[j].LSN })
+	return refs, nil
+}
+
+// Lock creates a Lease naming this process as the holder; it fails if a
+// non-expired lease already exists. If the existing lease's RenewTime is
+// older than its LeaseDurationSeconds, the previous holder is assumed dead
+// (crashed before calling Unlock) and Lock takes it over instead of failing
+// forever, the same expired-lease takeover Terraform's kubernetes backend does.
+func (b *Backend) Lock(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: leaseName, Namespace: b.namespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr(leaseHolder),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+			LeaseDurationSeconds: ptr(int32(leaseSeconds)),
+		},
+	}
+
+	leases := b.client.CoordinationV1().Leases(b.namespace)
+	_, err := leases.Create(ctx, lease, metav1.CreateOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := leases.Get(ctx, leaseName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !leaseExpired(existing) {
+		return fmt.Errorf("kubernetes: lease %s/%s already held", b.namespace, leaseName)
+	}
+
+	existing.Spec.HolderIdentity = ptr(leaseHolder)
+	existing.Spec.AcquireTime = &now
+	existing.Spec.RenewTime = &now
+	existing.Spec.LeaseDurationSeconds = ptr(int32(leaseSeconds))
+	_, err = leases.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// leaseExpired 判断一个 Lease 的持有者是否已经失联：RenewTime（没有就退回
+// AcquireTime）加上它声明的 LeaseDurationSeconds 早于现在，说明上一个持有者
+// 要么崩溃了要么忘了续约，可以被新的 Lock 调用安全接管。
+func leaseExpired(lease *coordinationv1.Lease) bool {
+	renewedAt := lease.Spec.RenewTime
+	if renewedAt == nil {
+		renewedAt = lease.Spec.AcquireTime
+	}
+	if renewedAt == nil {
+		return true
+	}
+
+	duration := leaseSeconds
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = int(*lease.Spec.LeaseDurationSeconds)
+	}
+	return time.Since(renewedAt.Time) > time.Duration(duration)*time.Second
+}
+
+func (b *Backend) Unlock(ctx context.Context) error {
+	err := b.client.CoordinationV1().Leases(b.namespace).Delete(ctx, leaseName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func parseLSN(id string) (uint64, error) {
+	if id == "" {
+		return 0, errors.New("kubernetes: missing segment label")
+	}
+	name := strings.TrimSuffix(id, ".log")
+	return strconv.ParseUint(name, 10, 64)
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,136 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build s3
+
+// Package s3 implements vfs.SnapshotBackend on top of Amazon S3. It's kept
+// behind the "s3" build tag so that core installs don't pull in the AWS SDK.
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/auula/momentdb/vfs"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// lockObjectKey is a well-known object that acts as the single-writer lock.
+// Its presence is enough for the coarse locking momentdb needs: Lock creates
+// it only if it does not exist yet, and Unlock deletes it unconditionally.
+// It is not meant to survive network partitions.
+const lockObjectKey = "momentdb.lock"
+
+// Backend stores sealed log files as objects under a single bucket/prefix.
+type Backend struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// New creates a Backend that stores segments under bucket/prefix.
+func New(client *s3.Client, bucket, prefix string) *Backend {
+	return &Backend{client: client, bucket: bucket, prefix: strings.TrimSuffix(prefix, "/")}
+}
+
+func (b *Backend) objectKey(id string) string {
+	return b.prefix + "/" + id
+}
+
+func (b *Backend) PutSegment(ctx context.Context, id string, r io.Reader) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(id)),
+		Body:   r,
+	})
+	return err
+}
+
+func (b *Backend) GetSegment(ctx context.Context, id string) (io.ReadCloser, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(id)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// ListSince lists every object under the prefix and returns the ones whose
+// LSN, encoded as the decimal object id before ".log" ("0000000042.log"), is
+// strictly greater than lsn. Objects whose id does not parse that way are skipped.
+func (b *Backend) ListSince(ctx context.Context, lsn uint64) ([]vfs.SegmentRef, error) {
+	var refs []vfs.SegmentRef
+
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(b.prefix + "/"),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			id := strings.TrimPrefix(aws.ToString(obj.Key), b.prefix+"/")
+			if id == lockObjectKey {
+				continue
+			}
+			segmentLSN, err := parseLSN(id)
+			if err != nil {
+				continue
+			}
+			if segmentLSN > lsn {
+				refs = append(refs, vfs.SegmentRef{ID: id, LSN: segmentLSN})
+			}
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].LSN < refs[j].LSN })
+	return refs, nil
+}
+
+func (b *Backend) Lock(ctx context.Context) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(b.objectKey(lockObjectKey)),
+		Body:        strings.NewReader(""),
+		IfNoneMatch: aws.String("*"),
+	})
+	if err != nil {
+		return fmt.Errorf("s3: acquire lock: %w", err)
+	}
+	return nil
+}
+
+func (b *Backend) Unlock(ctx context.Context) error {
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(lockObjectKey)),
+	})
+	return err
+}
+
+func parseLSN(id string) (uint64, error) {
+	name := strings.TrimSuffix(id, ".log")
+	return strconv.ParseUint(name, 10, 64)
+}
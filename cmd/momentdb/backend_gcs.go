@@ -0,0 +1,47 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build gcs
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/auula/momentdb/backend/gcs"
+	"github.com/auula/momentdb/vfs"
+
+	gcstorage "cloud.google.com/go/storage"
+)
+
+func init() {
+	backendFactories["gcs"] = newGCSBackend
+}
+
+func newGCSBackend() (vfs.SnapshotBackend, error) {
+	bucket := os.Getenv("MOMENTDB_GCS_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("MOMENTDB_GCS_BUCKET is required")
+	}
+	prefix := os.Getenv("MOMENTDB_GCS_PREFIX")
+
+	client, err := gcstorage.NewClient(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return gcs.New(client, bucket, prefix), nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build kubernetes
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	momentkube "github.com/auula/momentdb/backend/kubernetes"
+	"github.com/auula/momentdb/vfs"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func init() {
+	backendFactories["kubernetes"] = newKubernetesBackend
+}
+
+func newKubernetesBackend() (vfs.SnapshotBackend, error) {
+	namespace := os.Getenv("MOMENTDB_K8S_NAMESPACE")
+	if namespace == "" {
+		return nil, fmt.Errorf("MOMENTDB_K8S_NAMESPACE is required")
+	}
+
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return momentkube.New(clientset, namespace), nil
+}
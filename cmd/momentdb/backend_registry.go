@@ -0,0 +1,23 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "github.com/auula/momentdb/vfs"
+
+// backendFactories maps a --from name to a constructor for that backend.
+// It's populated by init() in the backend_<name>.go files, each gated by
+// the matching "s3"/"gcs"/"kubernetes" build tag, so a binary only knows
+// about the backends it was actually built with.
+var backendFactories = map[string]func() (vfs.SnapshotBackend, error){}
@@ -0,0 +1,48 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build s3
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/auula/momentdb/backend/s3"
+	"github.com/auula/momentdb/vfs"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func init() {
+	backendFactories["s3"] = newS3Backend
+}
+
+func newS3Backend() (vfs.SnapshotBackend, error) {
+	bucket := os.Getenv("MOMENTDB_S3_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("MOMENTDB_S3_BUCKET is required")
+	}
+	prefix := os.Getenv("MOMENTDB_S3_PREFIX")
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.New(awss3.NewFromConfig(cfg), bucket, prefix), nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/auula/momentdb/types"
+	"github.com/auula/momentdb/vfs"
+)
+
+// codecsByName maps the --from/--to flag values accepted by `momentdb migrate`
+// to the registered types.Codec, so the flag stays in sync with whichever
+// codecs were actually linked into the binary.
+var codecsByName = map[string]types.Codec{
+	"bson":    types.BSON,
+	"json":    types.JSON,
+	"msgpack": types.MsgPack,
+}
+
+// runMigrate implements `momentdb migrate --in=<path> --out=<path> --from=<codec> --to=<codec>`.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	in := fs.String("in", "", "path to the source log file")
+	out := fs.String("out", "", "path to write the migrated log file")
+	from := fs.String("from", "bson", "codec the source log was written with")
+	to := fs.String("to", "", "codec to rewrite matching records with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *in == "" || *out == "" {
+		return fmt.Errorf("--in and --out are required")
+	}
+	if *to == "" {
+		return fmt.Errorf("--to is required, available codecs: %s", strings.Join(availableCodecs(), ", "))
+	}
+
+	fromCodec, ok := codecsByName[*from]
+	if !ok {
+		return fmt.Errorf("unknown codec %q, available: %s", *from, strings.Join(availableCodecs(), ", "))
+	}
+	toCodec, ok := codecsByName[*to]
+	if !ok {
+		return fmt.Errorf("unknown codec %q, available: %s", *to, strings.Join(availableCodecs(), ", "))
+	}
+
+	result, err := vfs.MigrateCodec(*in, *out, fromCodec, toCodec)
+	if err != nil {
+		return fmt.Errorf("migrate %q to %q: %w", *in, *out, err)
+	}
+
+	fmt.Printf("migrated %d records from %q to %q, skipped %d\n", result.Migrated, *from, *to, result.Skipped)
+	return nil
+}
+
+func availableCodecs() []string {
+	names := make([]string, 0, len(codecsByName))
+	for name := range codecsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
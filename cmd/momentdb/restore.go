@@ -0,0 +1,82 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/auula/momentdb/vfs"
+)
+
+// runRestore implements `momentdb restore --from=<backend> --out=<path> [--since=<lsn>]`.
+// The backend is looked up in backendFactories, which is populated at
+// compile time by whichever backend/ build tags were enabled — a binary
+// built without, say, "-tags s3" simply doesn't know the name "s3". Backend
+// credentials come from environment variables (see backend_s3.go and friends),
+// not a flag, so there is no --dsn here.
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	from := fs.String("from", "", "name of the configured snapshot backend (s3, gcs, kubernetes)")
+	out := fs.String("out", "", "path to write the restored local log file to")
+	since := fs.Uint64("since", 0, "only replay segments with LSN greater than this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *from == "" {
+		return fmt.Errorf("--from is required, available backends: %s", strings.Join(availableBackends(), ", "))
+	}
+	if *out == "" {
+		return fmt.Errorf("--out is required")
+	}
+
+	factory, ok := backendFactories[*from]
+	if !ok {
+		return fmt.Errorf("unknown backend %q, available: %s", *from, strings.Join(availableBackends(), ", "))
+	}
+
+	backend, err := factory()
+	if err != nil {
+		return fmt.Errorf("configure backend %q: %w", *from, err)
+	}
+
+	ctx := context.Background()
+	result, err := vfs.Restore(ctx, backend, *since, *out)
+	if err != nil {
+		return fmt.Errorf("restore from %q: %w", *from, err)
+	}
+
+	fmt.Printf("restored %d keys from %q into %q (since lsn %d)\n", len(result.Index), *from, *out, *since)
+	if result.TornAt >= 0 {
+		fmt.Printf("warning: replay stopped at byte offset %d of the last segment\n", result.TornAt)
+	}
+	return nil
+}
+
+func availableBackends() []string {
+	names := make([]string, 0, len(backendFactories))
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"none (binary built without any backend/ build tags)"}
+	}
+	return names
+}
@@ -0,0 +1,677 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/auula/momentdb/types"
+	"github.com/auula/momentdb/vfs"
+)
+
+// ErrWrongType 对应 Redis 的 WRONGTYPE，当 key 存在但底层 Kind 不匹配时返回
+var ErrWrongType = errors.New("Operation against a key holding the wrong kind of value")
+
+// Handler 处理一条已经解析好的命令，把结果写回客户端
+type Handler func(c *conn, args []string) error
+
+// commands 是动词到处理函数的静态派发表，key 必须是大写命令名
+var commands = map[string]Handler{
+	"PING":          cmdPing,
+	"HELLO":         cmdHello,
+	"AUTH":          cmdAuth,
+	"GET":           cmdGet,
+	"SET":           cmdSet,
+	"DEL":           cmdDel,
+	"EXPIRE":        cmdExpire,
+	"TTL":           cmdTTL,
+	"INCR":          cmdIncr,
+	"DECRBY":        cmdDecrBy,
+	"INCRBYFLOAT":   cmdIncrByFloat,
+	"SADD":          cmdSAdd,
+	"SMEMBERS":      cmdSMembers,
+	"SINTER":        cmdSInter,
+	"ZADD":          cmdZAdd,
+	"ZRANGEBYSCORE": cmdZRangeByScore,
+	"LPUSH":         cmdLPush,
+	"LRANGE":        cmdLRange,
+	"LPOP":          cmdLPop,
+	"HSET":          cmdHSet,
+	"HGETALL":       cmdHGetAll,
+	"SUBSCRIBE":     cmdSubscribe,
+	"PUBLISH":       cmdPublish,
+}
+
+func cmdPing(c *conn, args []string) error {
+	if len(args) == 0 {
+		return c.w.WriteSimple("PONG")
+	}
+	return c.w.WriteBulk(args[0])
+}
+
+// cmdHello 处理 RESP3 的协议协商握手，参数为空时保持 RESP2
+func cmdHello(c *conn, args []string) error {
+	if len(args) > 0 && args[0] == "3" {
+		c.w.SetProtocol(true)
+	} else {
+		c.w.SetProtocol(false)
+	}
+	return c.w.WriteSimple("OK")
+}
+
+func cmdAuth(c *conn, args []string) error {
+	if len(args) != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'auth' command"))
+	}
+	if c.server.auth == nil {
+		return c.w.WriteError(errors.New("AUTH called without any password configured"))
+	}
+	if !c.server.auth.Authenticate(args[0]) {
+		return c.w.WriteError(errors.New("invalid password"))
+	}
+	c.authenticated = true
+	return c.w.WriteSimple("OK")
+}
+
+func requireAuth(c *conn) error {
+	if c.server.auth != nil && !c.authenticated {
+		return errors.New("NOAUTH Authentication required")
+	}
+	return nil
+}
+
+func cmdGet(c *conn, args []string) error {
+	if len(args) != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'get' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteNil()
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	text, err := seg.ToText()
+	if err != nil {
+		return c.w.WriteError(ErrWrongType)
+	}
+	return c.w.WriteBulk(text.String())
+}
+
+func cmdSet(c *conn, args []string) error {
+	if len(args) < 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'set' command"))
+	}
+	var ttl uint64
+	if len(args) >= 4 && args[2] == "EX" {
+		seconds, err := strconv.ParseUint(args[3], 10, 64)
+		if err != nil {
+			return c.w.WriteError(fmt.Errorf("invalid expire time in 'set' command"))
+		}
+		ttl = seconds
+	}
+	seg, err := vfs.NewSegment(args[0], types.NewText(args[1]), ttl, nil)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], seg); err != nil {
+		return c.w.WriteError(err)
+	}
+	c.server.notify("set", args[0])
+	return c.w.WriteSimple("OK")
+}
+
+func cmdDel(c *conn, args []string) error {
+	deleted := int64(0)
+	for _, key := range args {
+		if err := c.server.store.Delete(key); err == nil {
+			deleted++
+			c.server.notify("del", key)
+		}
+	}
+	return c.w.WriteInteger(deleted)
+}
+
+func cmdExpire(c *conn, args []string) error {
+	if len(args) != 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'expire' command"))
+	}
+	seconds, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return c.w.WriteError(fmt.Errorf("invalid expire time"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteInteger(0)
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	codec, err := types.CodecByID(seg.Codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	renewed, err := vfs.NewSegment(args[0], segmentValue(seg), seconds, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], renewed); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteInteger(1)
+}
+
+func cmdTTL(c *conn, args []string) error {
+	if len(args) != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'ttl' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteInteger(-2)
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	ttl := seg.TTL()
+	if ttl < 0 {
+		return c.w.WriteInteger(-1)
+	}
+	return c.w.WriteInteger(ttl / int64(1e9))
+}
+
+func cmdIncr(c *conn, args []string) error {
+	if len(args) != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'incr' command"))
+	}
+	number, codec, ttl, err := loadOrInitNumber(c.server.store, args[0])
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	value := number.Increment()
+	seg, err := vfs.NewSegment(args[0], number, ttl, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], seg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteInteger(value)
+}
+
+func cmdDecrBy(c *conn, args []string) error {
+	if len(args) != 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'decrby' command"))
+	}
+	delta, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return c.w.WriteError(fmt.Errorf("value is not an integer or out of range"))
+	}
+	number, codec, ttl, err := loadOrInitNumber(c.server.store, args[0])
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	value := number.Sub(delta)
+	seg, err := vfs.NewSegment(args[0], number, ttl, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], seg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteInteger(value)
+}
+
+// cmdIncrByFloat 实现 Redis 的 INCRBYFLOAT 语义：结果按字符串格式化并去掉多余的尾随零
+func cmdIncrByFloat(c *conn, args []string) error {
+	if len(args) != 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'incrbyfloat' command"))
+	}
+	delta, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return c.w.WriteError(fmt.Errorf("value is not a valid float"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	var value *types.Float
+	var codec types.Codec
+	var ttl uint64
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		value = types.NewFloat(0)
+	} else if err != nil {
+		return c.w.WriteError(err)
+	} else {
+		value, err = seg.ToFloat()
+		if err != nil {
+			return c.w.WriteError(ErrWrongType)
+		}
+		codec, err = types.CodecByID(seg.Codec)
+		if err != nil {
+			return c.w.WriteError(err)
+		}
+		ttl = ttlSeconds(seg)
+	}
+	result := value.Add(delta)
+	newSeg, err := vfs.NewSegment(args[0], value, ttl, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], newSeg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteBulk(strconv.FormatFloat(result, 'f', -1, 64))
+}
+
+// ttlSeconds 把 seg 剩余的 TTL 换算成 NewSegment 要求的整数秒，续写同一个 key 时用它
+// 而不是硬编码 0，否则之前用 EXPIRE/SET ... EX 设置的过期时间会在每次 RMW 命令后被
+// 悄悄清除。没有过期时间或者已经过期时返回 0（即续写后不再过期）。
+func ttlSeconds(seg *vfs.Segment) uint64 {
+	remaining := seg.TTL()
+	if remaining <= 0 {
+		return 0
+	}
+	seconds := uint64(remaining) / uint64(time.Second)
+	if seconds == 0 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// loadOrInitNumber 读出 key 对应的 Number，key 不存在时返回一个初始值为 0 的新 Number；
+// 同时返回原记录所用的 codec（新 key 时为 nil），调用方写回时要带上它，否则续写会把
+// 记录悄悄地改写成默认的 BSON 编码
+func loadOrInitNumber(store Store, key string) (*types.Number, types.Codec, uint64, error) {
+	seg, err := store.Get(key)
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return types.NewNumber(0), nil, 0, nil
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	number, err := seg.ToNumber()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	codec, err := types.CodecByID(seg.Codec)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return number, codec, ttlSeconds(seg), nil
+}
+
+func cmdSAdd(c *conn, args []string) error {
+	if len(args) < 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'sadd' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	var set *types.Set
+	var codec types.Codec
+	var ttl uint64
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		set = types.NewSet()
+	} else if err != nil {
+		return c.w.WriteError(err)
+	} else {
+		set, err = seg.ToSet()
+		if err != nil {
+			return c.w.WriteError(ErrWrongType)
+		}
+		codec, err = types.CodecByID(seg.Codec)
+		if err != nil {
+			return c.w.WriteError(err)
+		}
+		ttl = ttlSeconds(seg)
+	}
+	added := int64(0)
+	for _, member := range args[1:] {
+		if set.Add(member) {
+			added++
+		}
+	}
+	newSeg, err := vfs.NewSegment(args[0], set, ttl, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], newSeg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteInteger(added)
+}
+
+func cmdSMembers(c *conn, args []string) error {
+	if len(args) != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'smembers' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteArray(0)
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	set, err := seg.ToSet()
+	if err != nil {
+		return c.w.WriteError(ErrWrongType)
+	}
+	return c.w.WriteStringArray(set.Members())
+}
+
+func cmdSInter(c *conn, args []string) error {
+	if len(args) < 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'sinter' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	result, err := seg.ToSet()
+	if err != nil {
+		return c.w.WriteError(ErrWrongType)
+	}
+	for _, key := range args[1:] {
+		seg, err := c.server.store.Get(key)
+		if err != nil {
+			return c.w.WriteError(err)
+		}
+		other, err := seg.ToSet()
+		if err != nil {
+			return c.w.WriteError(ErrWrongType)
+		}
+		result = result.Intersect(other)
+	}
+	return c.w.WriteStringArray(result.Members())
+}
+
+func cmdZAdd(c *conn, args []string) error {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'zadd' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	var zset *types.ZSet
+	var codec types.Codec
+	var ttl uint64
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		zset = types.NewZSet()
+	} else if err != nil {
+		return c.w.WriteError(err)
+	} else {
+		zset, err = seg.ToZSet()
+		if err != nil {
+			return c.w.WriteError(ErrWrongType)
+		}
+		codec, err = types.CodecByID(seg.Codec)
+		if err != nil {
+			return c.w.WriteError(err)
+		}
+		ttl = ttlSeconds(seg)
+	}
+	added := int64(0)
+	for i := 1; i < len(args); i += 2 {
+		score, err := strconv.ParseFloat(args[i], 64)
+		if err != nil {
+			return c.w.WriteError(fmt.Errorf("value is not a valid float"))
+		}
+		if zset.Add(args[i+1], score) {
+			added++
+		}
+	}
+	newSeg, err := vfs.NewSegment(args[0], zset, ttl, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], newSeg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteInteger(added)
+}
+
+func cmdZRangeByScore(c *conn, args []string) error {
+	if len(args) != 3 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'zrangebyscore' command"))
+	}
+	min, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return c.w.WriteError(fmt.Errorf("min or max is not a float"))
+	}
+	max, err := strconv.ParseFloat(args[2], 64)
+	if err != nil {
+		return c.w.WriteError(fmt.Errorf("min or max is not a float"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteArray(0)
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	zset, err := seg.ToZSet()
+	if err != nil {
+		return c.w.WriteError(ErrWrongType)
+	}
+	return c.w.WriteStringArray(zset.RangeByScore(min, max))
+}
+
+func cmdLPush(c *conn, args []string) error {
+	if len(args) < 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'lpush' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	var list *types.List
+	var codec types.Codec
+	var ttl uint64
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		list = types.NewList()
+	} else if err != nil {
+		return c.w.WriteError(err)
+	} else {
+		list, err = seg.ToList()
+		if err != nil {
+			return c.w.WriteError(ErrWrongType)
+		}
+		codec, err = types.CodecByID(seg.Codec)
+		if err != nil {
+			return c.w.WriteError(err)
+		}
+		ttl = ttlSeconds(seg)
+	}
+	for _, value := range args[1:] {
+		list.PushFront(value)
+	}
+	newSeg, err := vfs.NewSegment(args[0], list, ttl, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], newSeg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteInteger(int64(list.Len()))
+}
+
+func cmdLRange(c *conn, args []string) error {
+	if len(args) != 3 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'lrange' command"))
+	}
+	start, err := strconv.Atoi(args[1])
+	if err != nil {
+		return c.w.WriteError(fmt.Errorf("value is not an integer or out of range"))
+	}
+	stop, err := strconv.Atoi(args[2])
+	if err != nil {
+		return c.w.WriteError(fmt.Errorf("value is not an integer or out of range"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteArray(0)
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	list, err := seg.ToList()
+	if err != nil {
+		return c.w.WriteError(ErrWrongType)
+	}
+	return c.w.WriteStringArray(list.Range(start, stop))
+}
+
+func cmdLPop(c *conn, args []string) error {
+	if len(args) != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'lpop' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteNil()
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	list, err := seg.ToList()
+	if err != nil {
+		return c.w.WriteError(ErrWrongType)
+	}
+	value, ok := list.PopFront()
+	if !ok {
+		return c.w.WriteNil()
+	}
+	codec, err := types.CodecByID(seg.Codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	newSeg, err := vfs.NewSegment(args[0], list, ttlSeconds(seg), codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], newSeg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteBulk(value)
+}
+
+func cmdHSet(c *conn, args []string) error {
+	if len(args) < 3 || len(args)%2 != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'hset' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	var table *types.Table
+	var codec types.Codec
+	var ttl uint64
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		table = types.NewTable()
+	} else if err != nil {
+		return c.w.WriteError(err)
+	} else {
+		table, err = seg.ToTable()
+		if err != nil {
+			return c.w.WriteError(ErrWrongType)
+		}
+		codec, err = types.CodecByID(seg.Codec)
+		if err != nil {
+			return c.w.WriteError(err)
+		}
+		ttl = ttlSeconds(seg)
+	}
+	added := int64(0)
+	for i := 1; i < len(args); i += 2 {
+		if table.Set(args[i], args[i+1]) {
+			added++
+		}
+	}
+	newSeg, err := vfs.NewSegment(args[0], table, ttl, codec)
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	if err := c.server.store.Put(args[0], newSeg); err != nil {
+		return c.w.WriteError(err)
+	}
+	return c.w.WriteInteger(added)
+}
+
+func cmdHGetAll(c *conn, args []string) error {
+	if len(args) != 1 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'hgetall' command"))
+	}
+	seg, err := c.server.store.Get(args[0])
+	if errors.Is(err, vfs.ErrKeyNotFound) {
+		return c.w.WriteArray(0)
+	}
+	if err != nil {
+		return c.w.WriteError(err)
+	}
+	table, err := seg.ToTable()
+	if err != nil {
+		return c.w.WriteError(ErrWrongType)
+	}
+	return c.w.WriteStringArray(table.Flatten())
+}
+
+func cmdSubscribe(c *conn, args []string) error {
+	if len(args) == 0 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'subscribe' command"))
+	}
+	for _, channel := range args {
+		c.server.hub.Subscribe(channel, c)
+		if err := c.w.WriteArray(3); err != nil {
+			return err
+		}
+		if err := c.w.WriteBulk("subscribe"); err != nil {
+			return err
+		}
+		if err := c.w.WriteBulk(channel); err != nil {
+			return err
+		}
+		if err := c.w.WriteInteger(1); err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+func cmdPublish(c *conn, args []string) error {
+	if len(args) != 2 {
+		return c.w.WriteError(fmt.Errorf("wrong number of arguments for 'publish' command"))
+	}
+	n := c.server.hub.Publish(args[0], args[1])
+	return c.w.WriteInteger(int64(n))
+}
+
+// segmentValue 把一个已经落盘的 Segment 转换回可以重新写入 NewSegment 的 Serializable，
+// EXPIRE 命令用它在不改变值的前提下续期。
+func segmentValue(seg *vfs.Segment) vfs.Serializable {
+	switch seg.Type {
+	case vfs.Text:
+		v, _ := seg.ToText()
+		return v
+	case vfs.Number:
+		v, _ := seg.ToNumber()
+		return v
+	case vfs.Float:
+		v, _ := seg.ToFloat()
+		return v
+	case vfs.Set:
+		v, _ := seg.ToSet()
+		return v
+	case vfs.ZSet:
+		v, _ := seg.ToZSet()
+		return v
+	case vfs.List:
+		v, _ := seg.ToList()
+		return v
+	case vfs.Table:
+		v, _ := seg.ToTable()
+		return v
+	default:
+		return nil
+	}
+}
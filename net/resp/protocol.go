@@ -0,0 +1,267 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resp 实现了 RESP2/RESP3 协议的解析与编码，使现有的 Redis 客户端
+// 可以直接连接 MomentDB，而无需编写专用的 SDK。
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrProtocol 表示读取到了不符合 RESP 规范的数据帧
+var ErrProtocol = errors.New("resp: protocol error")
+
+// maxArrayLen 和 maxBulkLen 是单条帧里数组元素个数/批量字符串长度的上限。这两个
+// 长度都直接来自客户端发来的、还没读到内容的头部，连接还没通过 requireAuth 校验
+// 时就会被解析；不设上限的话，一个 `*2000000000\r\n` 或 `$2000000000\r\n` 就能让
+// 服务端按照这个数字去 make() 几 GB 内存，类比 Redis 的 proto-max-bulk-len
+const (
+	maxArrayLen = 1024 * 1024
+	maxBulkLen  = 512 << 20
+)
+
+// Kind 标识一个 RESP 值的类型前缀
+type Kind byte
+
+const (
+	Simple  Kind = '+'
+	Error   Kind = '-'
+	Integer Kind = ':'
+	Bulk    Kind = '$'
+	Array   Kind = '*'
+	// RESP3 新增的前缀
+	Null    Kind = '_'
+	Boolean Kind = '#'
+	Double  Kind = ','
+	Map     Kind = '%'
+	Push    Kind = '>'
+)
+
+// Value 是解析之后的一条 RESP 消息，Array 用于承载多条批量字符串（即客户端命令）
+type Value struct {
+	Kind  Kind
+	Str   string
+	Int   int64
+	Array []Value
+	IsNil bool
+}
+
+// Command 将一个 Array Value 转换为大写的命令名和剩余参数
+func (v Value) Command() (string, []string, error) {
+	if v.Kind != Array || len(v.Array) == 0 {
+		return "", nil, fmt.Errorf("%w: expected command array", ErrProtocol)
+	}
+	args := make([]string, len(v.Array))
+	for i, item := range v.Array {
+		if item.Kind != Bulk && item.Kind != Simple {
+			return "", nil, fmt.Errorf("%w: command argument must be a string", ErrProtocol)
+		}
+		args[i] = item.Str
+	}
+	return strings.ToUpper(args[0]), args[1:], nil
+}
+
+// Reader 按照 RESP2/RESP3 语法从连接中逐条读取客户端帧，同时兼容内联命令
+type Reader struct {
+	br *bufio.Reader
+}
+
+func NewReader(br *bufio.Reader) *Reader {
+	return &Reader{br: br}
+}
+
+// ReadValue 读取下一条完整的 RESP 值
+func (r *Reader) ReadValue() (Value, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return Value{}, err
+	}
+	if len(line) == 0 {
+		return Value{}, fmt.Errorf("%w: empty line", ErrProtocol)
+	}
+
+	// 以 * $ + - : 开头的是标准帧，其余情况按照内联命令解析（方便 telnet 调试）
+	switch Kind(line[0]) {
+	case Array:
+		return r.readArray(line)
+	case Bulk:
+		return r.readBulk(line)
+	case Simple:
+		return Value{Kind: Simple, Str: line[1:]}, nil
+	case Error:
+		return Value{Kind: Error, Str: line[1:]}, nil
+	case Integer:
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return Value{}, fmt.Errorf("%w: bad integer", ErrProtocol)
+		}
+		return Value{Kind: Integer, Int: n}, nil
+	default:
+		return r.readInline(line)
+	}
+}
+
+func (r *Reader) readLine() (string, error) {
+	line, err := r.br.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (r *Reader) readArray(header string) (Value, error) {
+	n, err := strconv.Atoi(header[1:])
+	if err != nil {
+		return Value{}, fmt.Errorf("%w: bad array length", ErrProtocol)
+	}
+	if n < 0 {
+		return Value{Kind: Array, IsNil: true}, nil
+	}
+	if n > maxArrayLen {
+		return Value{}, fmt.Errorf("%w: array length %d exceeds %d element limit", ErrProtocol, n, maxArrayLen)
+	}
+	items := make([]Value, n)
+	for i := 0; i < n; i++ {
+		v, err := r.ReadValue()
+		if err != nil {
+			return Value{}, err
+		}
+		items[i] = v
+	}
+	return Value{Kind: Array, Array: items}, nil
+}
+
+func (r *Reader) readBulk(header string) (Value, error) {
+	n, err := strconv.Atoi(header[1:])
+	if err != nil {
+		return Value{}, fmt.Errorf("%w: bad bulk length", ErrProtocol)
+	}
+	if n < 0 {
+		return Value{Kind: Bulk, IsNil: true}, nil
+	}
+	if n > maxBulkLen {
+		return Value{}, fmt.Errorf("%w: bulk length %d exceeds %d byte limit", ErrProtocol, n, maxBulkLen)
+	}
+	buf := make([]byte, n+2) // 末尾的 \r\n
+	if _, err := readFull(r.br, buf); err != nil {
+		return Value{}, err
+	}
+	return Value{Kind: Bulk, Str: string(buf[:n])}, nil
+}
+
+// readInline 兼容 `GET foo\r\n` 这种没有类型前缀的内联命令
+func (r *Reader) readInline(line string) (Value, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Value{}, fmt.Errorf("%w: empty inline command", ErrProtocol)
+	}
+	items := make([]Value, len(fields))
+	for i, f := range fields {
+		items[i] = Value{Kind: Bulk, Str: f}
+	}
+	return Value{Kind: Array, Array: items}, nil
+}
+
+func readFull(br *bufio.Reader, buf []byte) (int, error) {
+	read := 0
+	for read < len(buf) {
+		n, err := br.Read(buf[read:])
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+// Writer 按照客户端握手时协商的协议版本（RESP2/RESP3）编码回复
+type Writer struct {
+	bw    *bufio.Writer
+	resp3 bool
+}
+
+func NewWriter(bw *bufio.Writer) *Writer {
+	return &Writer{bw: bw}
+}
+
+// SetProtocol 切换到 RESP3（由 HELLO 3 命令触发）
+func (w *Writer) SetProtocol(resp3 bool) {
+	w.resp3 = resp3
+}
+
+func (w *Writer) WriteSimple(s string) error {
+	_, err := fmt.Fprintf(w.bw, "+%s\r\n", s)
+	return err
+}
+
+func (w *Writer) WriteError(err error) error {
+	_, werr := fmt.Fprintf(w.bw, "-ERR %s\r\n", err.Error())
+	return werr
+}
+
+func (w *Writer) WriteInteger(n int64) error {
+	_, err := fmt.Fprintf(w.bw, ":%d\r\n", n)
+	return err
+}
+
+func (w *Writer) WriteBulk(s string) error {
+	_, err := fmt.Fprintf(w.bw, "$%d\r\n%s\r\n", len(s), s)
+	return err
+}
+
+// WriteNil 根据协议版本写出 RESP2 的 $-1 或 RESP3 的 _
+func (w *Writer) WriteNil() error {
+	if w.resp3 {
+		_, err := w.bw.WriteString("_\r\n")
+		return err
+	}
+	_, err := w.bw.WriteString("$-1\r\n")
+	return err
+}
+
+// WriteDouble 写出浮点数，RESP3 下使用原生的 , 前缀，否则退化为批量字符串
+func (w *Writer) WriteDouble(f float64) error {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if w.resp3 {
+		_, err := fmt.Fprintf(w.bw, ",%s\r\n", s)
+		return err
+	}
+	return w.WriteBulk(s)
+}
+
+func (w *Writer) WriteArray(n int) error {
+	_, err := fmt.Fprintf(w.bw, "*%d\r\n", n)
+	return err
+}
+
+func (w *Writer) WriteStringArray(items []string) error {
+	if err := w.WriteArray(len(items)); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := w.WriteBulk(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) Flush() error {
+	return w.bw.Flush()
+}
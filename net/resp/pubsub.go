@@ -0,0 +1,67 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resp
+
+import "sync"
+
+// Hub 维护频道到订阅连接的映射，用于普通的 PUBLISH/SUBSCRIBE 以及
+// 墓碑写入触发的 keyspace 通知（__keyspace@0__:<key>）。
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[string]map[*conn]struct{}
+}
+
+func newHub() *Hub {
+	return &Hub{subs: make(map[string]map[*conn]struct{})}
+}
+
+func (h *Hub) Subscribe(channel string, c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[channel] == nil {
+		h.subs[channel] = make(map[*conn]struct{})
+	}
+	h.subs[channel][c] = struct{}{}
+}
+
+// UnsubscribeAll 在连接断开时清理其在所有频道下的订阅
+func (h *Hub) UnsubscribeAll(c *conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for channel, subscribers := range h.subs {
+		delete(subscribers, c)
+		if len(subscribers) == 0 {
+			delete(h.subs, channel)
+		}
+	}
+}
+
+// Publish 把消息投递给频道下的每一个订阅者，返回实际收到消息的连接数
+func (h *Hub) Publish(channel, payload string) int {
+	h.mu.RLock()
+	subscribers := make([]*conn, 0, len(h.subs[channel]))
+	for c := range h.subs[channel] {
+		subscribers = append(subscribers, c)
+	}
+	h.mu.RUnlock()
+
+	delivered := 0
+	for _, c := range subscribers {
+		if c.publish(channel, payload) == nil {
+			delivered++
+		}
+	}
+	return delivered
+}
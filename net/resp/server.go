@@ -0,0 +1,188 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/auula/momentdb/clog"
+)
+
+// Authenticator 校验 AUTH 命令携带的密码，真正的凭据来源于管理后台的配置
+type Authenticator interface {
+	Authenticate(password string) bool
+}
+
+// StaticAuth 是最简单的 Authenticator 实现，对应 Redis 的 requirepass
+type StaticAuth string
+
+func (a StaticAuth) Authenticate(password string) bool {
+	return string(a) == password
+}
+
+// Server 是一个 RESP2/RESP3 的 TCP 前端，把解析出来的命令派发给底层 Store
+type Server struct {
+	Addr  string
+	store Store
+	auth  Authenticator
+	hub   *Hub
+
+	listener net.Listener
+}
+
+// NewServer 创建一个尚未开始监听的 RESP 服务端，auth 传 nil 表示不需要鉴权
+func NewServer(addr string, store Store, auth Authenticator) *Server {
+	return &Server{
+		Addr:  addr,
+		store: store,
+		auth:  auth,
+		hub:   newHub(),
+	}
+}
+
+// ListenAndServe 开始监听并阻塞式地接受连接，每个连接由独立的 goroutine 处理
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = ln
+	clog.Info("resp: listening on " + s.Addr)
+
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			clog.Error(err.Error())
+			continue
+		}
+		c := &conn{
+			server:        s,
+			nc:            nc,
+			r:             NewReader(bufio.NewReader(nc)),
+			w:             NewWriter(bufio.NewWriter(nc)),
+			authenticated: s.auth == nil,
+		}
+		go c.serve()
+	}
+}
+
+// Close 停止接受新连接，已经建立的连接会在下一次读取失败时自行退出
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+func (s *Server) notify(event, key string) {
+	s.hub.Publish("__keyspace@0__:"+key, event)
+}
+
+// conn 承载了单个客户端连接的读写状态，命令按照到达顺序串行处理。客户端可以
+// 不等回复就连续发送多条命令（管道化），它们缓冲在 bufio.Reader 里依次解析，
+// 每条命令的回复在处理完之后立即 Flush，然后才解析下一条。
+//
+// writeMu 保护 w 本身：serve() 所在的 goroutine 写回命令结果的同时，
+// 另一个客户端调用 PUBLISH 可能会在 Hub.Publish 里从它自己的 serve()
+// goroutine 跨连接调用这里的 publish()，两边都往同一个 bufio.Writer
+// 里写；bufio.Writer 不是并发安全的，没有这把锁的话两次写入/Flush
+// 可能在 TCP 流里交错，使这个订阅者收到的 RESP 帧损坏。
+type conn struct {
+	server        *Server
+	nc            net.Conn
+	r             *Reader
+	w             *Writer
+	writeMu       sync.Mutex
+	authenticated bool
+}
+
+func (c *conn) serve() {
+	defer c.nc.Close()
+	defer c.server.hub.UnsubscribeAll(c)
+
+	for {
+		value, err := c.r.ReadValue()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				clog.Error(err.Error())
+			}
+			return
+		}
+
+		name, args, err := value.Command()
+		if err != nil {
+			c.writeMu.Lock()
+			_ = c.w.WriteError(err)
+			_ = c.w.Flush()
+			c.writeMu.Unlock()
+			continue
+		}
+
+		if name != "AUTH" && name != "HELLO" && name != "PING" {
+			if err := requireAuth(c); err != nil {
+				c.writeMu.Lock()
+				_ = c.w.WriteError(err)
+				_ = c.w.Flush()
+				c.writeMu.Unlock()
+				continue
+			}
+		}
+
+		handler, ok := commands[name]
+		if !ok {
+			c.writeMu.Lock()
+			_ = c.w.WriteError(errors.New("unknown command '" + name + "'"))
+			_ = c.w.Flush()
+			c.writeMu.Unlock()
+			continue
+		}
+
+		c.writeMu.Lock()
+		if err := handler(c, args); err != nil {
+			_ = c.w.WriteError(err)
+		}
+		err = c.w.Flush()
+		c.writeMu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// publish 把一条 message 推送帧写给这个订阅连接并立即 Flush，由 Hub.Publish 调用
+func (c *conn) publish(channel, payload string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.w.WriteArray(3); err != nil {
+		return err
+	}
+	if err := c.w.WriteBulk("message"); err != nil {
+		return err
+	}
+	if err := c.w.WriteBulk(channel); err != nil {
+		return err
+	}
+	if err := c.w.WriteBulk(payload); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
@@ -0,0 +1,32 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resp
+
+import (
+	"github.com/auula/momentdb/vfs"
+)
+
+// Store 是 RESP 前端与底层存储引擎之间的最小接口，真正的落盘、索引与压缩
+// 逻辑都在 vfs 的日志引擎中实现，这里只依赖读写 Segment 所需要的能力。
+type Store interface {
+	// Get 按照 key 读取一条未过期、未删除的 Segment，不存在时返回 vfs.ErrKeyNotFound
+	Get(key string) (*vfs.Segment, error)
+	// Put 写入一条新的 Segment，覆盖同名的旧记录
+	Put(key string, seg *vfs.Segment) error
+	// Delete 追加一条墓碑记录，逻辑删除 key
+	Delete(key string) error
+	// Keys 返回带有指定前缀的全部 key，用于 SCAN 类命令
+	Keys(prefix string) ([]string, error)
+}
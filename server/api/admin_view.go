@@ -1,17 +1,33 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
 package api
 
 import (
+	"embed"
+	"html/template"
 	"net/http"
-	"text/template"
 
-	"github.com/auula/vasedb/clog"
+	"github.com/auula/momentdb/clog"
+	"golang.org/x/crypto/bcrypt"
 )
 
-const (
-	// 默认的 HTML 文件文本
-	loginHtml     = "text/template"
-	dashboardHtml = "text/template"
-)
+//go:embed assets/templates/*.html
+var templateFS embed.FS
+
+//go:embed assets/static
+var staticFS embed.FS
 
 // AdminTemplates 结构体用于存储所有后台模板
 type AdminTemplates struct {
@@ -23,34 +39,72 @@ type AdminTemplates struct {
 var templates AdminTemplates
 
 func init() {
-	// 根据 html 文件来构造后台 view 的模版
-	templates.Login = template.Must(template.
-		New("login").
-		Parse(loginHtml))
-	templates.Dashboard = template.Must(template.
-		New("dashboard").
-		Parse(dashboardHtml))
+	templates.Login = template.Must(template.ParseFS(templateFS, "assets/templates/login.html"))
+	templates.Dashboard = template.Must(template.ParseFS(templateFS, "assets/templates/dashboard.html"))
 }
 
+// StaticHandler 提供 assets/static 下的 CSS/JS 资源
+func StaticHandler() http.Handler {
+	return http.StripPrefix("/static/", http.FileServer(http.FS(mustSub(staticFS, "assets/static"))))
+}
+
+// LoginHandler 渲染登录表单（GET）或者校验凭据并签发会话 cookie（POST）
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	// 使用 Login 渲染登录页面
-	data := map[string]interface{}{
-		"Msg": "使用 Login 渲染登录页面",
+	if r.Method == http.MethodPost {
+		handleLoginSubmit(w, r)
+		return
+	}
+
+	renderLogin(w, "")
+}
+
+func handleLoginSubmit(w http.ResponseWriter, r *http.Request) {
+	if !verifyLoginToken(r.FormValue(csrfFormField)) {
+		renderLogin(w, "invalid or expired form, please retry")
+		return
+	}
+
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+
+	if username != config.Credentials.Username {
+		renderLogin(w, "invalid username or password")
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(config.Credentials.PasswordHash), []byte(password)); err != nil {
+		renderLogin(w, "invalid username or password")
+		return
 	}
-	err := templates.Login.Execute(w, data)
-	if err != nil {
+
+	http.SetCookie(w, newSessionCookie(username))
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func renderLogin(w http.ResponseWriter, errMsg string) {
+	data := map[string]any{
+		"Error":     errMsg,
+		"CSRFToken": newLoginToken(),
+	}
+	if err := templates.Login.Execute(w, data); err != nil {
 		clog.Error(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// LogoutHandler 清除会话 cookie 并跳转回登录页
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, expiredSessionCookie())
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// DashboardHandler 渲染后台首页，要求先经过 RequireSession 中间件
 func DashboardHandler(w http.ResponseWriter, r *http.Request) {
-	// 使用 Dashboard 渲染仪表盘页面
-	data := map[string]interface{}{
-		"Msg": "使用 Dashboard 渲染仪表盘页面",
+	username := currentUsername(r.Context())
+	data := map[string]any{
+		"Username":  username,
+		"CSRFToken": csrfToken(username),
 	}
-	err := templates.Dashboard.Execute(w, data)
-	if err != nil {
+	if err := templates.Dashboard.Execute(w, data); err != nil {
 		clog.Error(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
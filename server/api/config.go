@@ -0,0 +1,44 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import "time"
+
+// Credentials 是后台登录校验所需要的配置，PasswordHash 保存的是 bcrypt 哈希，
+// 从来不会以明文形式出现在配置文件或者进程内存的其它地方
+type Credentials struct {
+	Username     string
+	PasswordHash string
+}
+
+// Config 承载了 admin 子系统运行所需要的全部配置项
+type Config struct {
+	Credentials Credentials
+	// Secret 用来对会话 cookie 和 CSRF token 做 HMAC 签名，长度建议不小于 32 字节
+	Secret []byte
+	// SessionTTL 是登录会话的有效期，超过之后 cookie 会被中间件当作过期处理
+	SessionTTL time.Duration
+}
+
+// config 是当前进程使用的 admin 配置，由调用方在启动时通过 Configure 设置
+var config Config
+
+// Configure 设置 admin 子系统使用的配置，必须在注册路由之前调用
+func Configure(cfg Config) {
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = 24 * time.Hour
+	}
+	config = cfg
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const csrfFormField = "csrf_token"
+
+// loginTokenTTL 是登录表单 nonce 的有效期，超过这个时间必须重新 GET /login 换一个
+const loginTokenTTL = 10 * time.Minute
+
+// csrfToken 用当前登录用户名派生一个 CSRF token，绑定在同一个 Secret 之下，
+// 表单提交时和这里重新计算出的值做常数时间比较。只用于已登录会话的表单（比如
+// dashboard），登录表单本身在用户还没有会话之前不能用这个派生值，见 loginTokens。
+func csrfToken(username string) string {
+	mac := hmac.New(sha256.New, config.Secret)
+	mac.Write([]byte("csrf:" + username))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyCSRF 校验表单里携带的 CSRF token 是否和当前会话匹配
+func verifyCSRF(r *http.Request, username string) bool {
+	token := r.FormValue(csrfFormField)
+	if token == "" {
+		return false
+	}
+	expected := csrfToken(username)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+// loginTokens 为还没有会话的登录表单保存一次性 nonce。csrfToken 对同一个用户名永远
+// 派生出同一个值，攻击者自己 GET /login 就能读到这个"秘密"再拿去伪造跨站 POST；
+// 登录表单必须换成每次请求都不同、服务端记住的随机值，并且用完即失效。
+var loginTokens = struct {
+	sync.Mutex
+	issued map[string]time.Time
+}{issued: make(map[string]time.Time)}
+
+// newLoginToken 签发一枚随机 nonce 并记录签发时间，供 renderLogin 嵌入表单。顺带清掉
+// 已经过期但从未被提交的旧 nonce，否则匿名反复 GET /login 会让 loginTokens.issued
+// 无限增长，这个端点本身不需要登录就能访问。
+func newLoginToken() string {
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		panic(err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw[:])
+
+	loginTokens.Lock()
+	defer loginTokens.Unlock()
+	sweepExpiredLoginTokens()
+	loginTokens.issued[token] = time.Now()
+	return token
+}
+
+// sweepExpiredLoginTokens 删除所有已经超过 loginTokenTTL、从未被兑现的 nonce；
+// 调用方必须已经持有 loginTokens 的锁。
+func sweepExpiredLoginTokens() {
+	now := time.Now()
+	for token, issuedAt := range loginTokens.issued {
+		if now.Sub(issuedAt) > loginTokenTTL {
+			delete(loginTokens.issued, token)
+		}
+	}
+}
+
+// verifyLoginToken 校验并消费一枚登录表单 nonce：必须存在、未过期，而且每个 nonce
+// 只能成功用一次，用完立即删除，防止同一个 token 被重放
+func verifyLoginToken(token string) bool {
+	if token == "" {
+		return false
+	}
+
+	loginTokens.Lock()
+	defer loginTokens.Unlock()
+
+	issuedAt, ok := loginTokens.issued[token]
+	delete(loginTokens.issued, token)
+	if !ok {
+		return false
+	}
+	return time.Since(issuedAt) <= loginTokenTTL
+}
+
+// RequireCSRF 包裹处理表单 POST 的 handler，POST 请求只有携带正确 CSRF token 才会放行，
+// 其它方法的请求不做校验直接放行；必须放在 RequireSession 之后使用，因为它依赖上下文里已经解析好的用户名
+func RequireCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && !verifyCSRF(r, currentUsername(r.Context())) {
+			http.Error(w, "invalid csrf token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
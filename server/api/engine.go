@@ -0,0 +1,53 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import "github.com/auula/momentdb/vfs"
+
+// Stats 汇总了仪表盘和 /metrics 都需要用到的运行时统计信息
+type Stats struct {
+	// KindCounts 按照 vfs.Kind 的名字统计存活记录数量，例如 "text"、"set"
+	KindCounts map[string]int64 `json:"kind_counts"`
+	// TombstoneRatio 是墓碑记录占日志总记录数的比例，用来判断是否需要压缩
+	TombstoneRatio float64 `json:"tombstone_ratio"`
+	// TTLHistogram 把带过期时间的 key 按照剩余时间分桶，桶的名字类似 "1m"、"1h"、"1d"、"forever"
+	TTLHistogram map[string]int64 `json:"ttl_histogram"`
+	// DiskBytes 是所有日志文件占用的磁盘字节数
+	DiskBytes int64 `json:"disk_bytes"`
+}
+
+// KeyPage 是 /api/keys 分页扫描的返回结果
+type KeyPage struct {
+	Keys       []string `json:"keys"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// Engine 是 admin 子系统依赖的最小存储引擎接口，真正的实现在上层的 vfs 存储引擎中，
+// 这里只声明仪表盘和运维 API 需要用到的只读统计、分页扫描和单 key 操作。
+type Engine interface {
+	Stats() (Stats, error)
+	// Scan 返回带有 prefix 前缀、cursor 之后的至多 limit 个 key，NextCursor 为空表示已经扫描完
+	Scan(prefix, cursor string, limit int) (KeyPage, error)
+	Get(key string) (*vfs.Segment, error)
+	Delete(key string) error
+}
+
+// engine 是当前进程使用的存储引擎，由调用方在启动时通过 SetEngine 设置
+var engine Engine
+
+// SetEngine 设置 admin JSON API 和 /metrics 使用的存储引擎，必须在注册路由之前调用
+func SetEngine(e Engine) {
+	engine = e
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/auula/momentdb/clog"
+	"github.com/auula/momentdb/vfs"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		clog.Error(err.Error())
+	}
+}
+
+// StatsHandler 对应 GET /api/stats，返回按 Kind 分类的记录数、墓碑比例、TTL 直方图和磁盘占用
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := engine.Stats()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// KeysHandler 对应 GET /api/keys?prefix=&cursor=&limit=，在索引上做分页扫描
+func KeysHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	cursor := r.URL.Query().Get("cursor")
+
+	limit := 100
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	page, err := engine.Scan(prefix, cursor, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// KeyHandler 对应 GET/DELETE /api/key/{k}，k 是路径中 /api/key/ 之后的剩余部分
+func KeyHandler(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/api/key/")
+	if key == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "missing key"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		seg, err := engine.Get(key)
+		if errors.Is(err, vfs.ErrKeyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "key not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, segmentSummary(key, seg))
+	case http.MethodDelete:
+		if err := engine.Delete(key); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func segmentSummary(key string, seg *vfs.Segment) map[string]any {
+	return map[string]any{
+		"key":        key,
+		"kind":       seg.Type,
+		"created_at": seg.CreatedAt,
+		"expired_at": seg.ExpiredAt,
+		"ttl":        seg.TTL(),
+		"size":       seg.Size(),
+	}
+}
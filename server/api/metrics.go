@@ -0,0 +1,62 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// MetricsHandler 对应 GET /metrics，用 Prometheus exposition 格式暴露和 /api/stats
+// 相同的计数器，方便 operator 直接用 Prometheus/Grafana 抓取，不需要额外的 exporter。
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := engine.Stats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP momentdb_segments_total Number of live segments by kind.")
+	fmt.Fprintln(w, "# TYPE momentdb_segments_total gauge")
+	for _, kind := range sortedKeys(stats.KindCounts) {
+		fmt.Fprintf(w, "momentdb_segments_total{kind=%q} %d\n", kind, stats.KindCounts[kind])
+	}
+
+	fmt.Fprintln(w, "# HELP momentdb_tombstone_ratio Fraction of log records that are tombstones.")
+	fmt.Fprintln(w, "# TYPE momentdb_tombstone_ratio gauge")
+	fmt.Fprintf(w, "momentdb_tombstone_ratio %f\n", stats.TombstoneRatio)
+
+	fmt.Fprintln(w, "# HELP momentdb_ttl_keys_total Number of keys with a TTL, bucketed by remaining lifetime.")
+	fmt.Fprintln(w, "# TYPE momentdb_ttl_keys_total gauge")
+	for _, bucket := range sortedKeys(stats.TTLHistogram) {
+		fmt.Fprintf(w, "momentdb_ttl_keys_total{bucket=%q} %d\n", bucket, stats.TTLHistogram[bucket])
+	}
+
+	fmt.Fprintln(w, "# HELP momentdb_disk_bytes Total size of the on-disk log files.")
+	fmt.Fprintln(w, "# TYPE momentdb_disk_bytes gauge")
+	fmt.Fprintf(w, "momentdb_disk_bytes %d\n", stats.DiskBytes)
+}
+
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,36 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import "net/http"
+
+// Routes 注册 admin 子系统的全部路由，调用方只需要把返回的 handler 挂到自己的
+// http.Server 上；Configure 和 SetEngine 必须先于 Routes 调用。
+func Routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("/static/", StaticHandler())
+	mux.HandleFunc("/login", LoginHandler)
+	mux.Handle("/logout", RequireSession(RequireCSRF(http.HandlerFunc(LogoutHandler))))
+	mux.Handle("/dashboard", RequireSession(http.HandlerFunc(DashboardHandler)))
+
+	mux.Handle("/api/stats", RequireSession(http.HandlerFunc(StatsHandler)))
+	mux.Handle("/api/keys", RequireSession(http.HandlerFunc(KeysHandler)))
+	mux.Handle("/api/key/", RequireSession(http.HandlerFunc(KeyHandler)))
+
+	mux.Handle("/metrics", RequireSession(http.HandlerFunc(MetricsHandler)))
+
+	return mux
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sessionCookieName = "momentdb_session"
+
+// newSessionCookie 为 username 签发一个带过期时间的会话 cookie，格式是
+// base64(username):expiresAt:base64(hmac)，中间件解析时逐段校验签名和有效期
+func newSessionCookie(username string) *http.Cookie {
+	expiresAt := time.Now().Add(config.SessionTTL).Unix()
+	value := signSession(username, expiresAt)
+	return &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Unix(expiresAt, 0),
+	}
+}
+
+func signSession(username string, expiresAt int64) string {
+	payload := fmt.Sprintf("%s:%d", base64.RawURLEncoding.EncodeToString([]byte(username)), expiresAt)
+	mac := hmac.New(sha256.New, config.Secret)
+	mac.Write([]byte(payload))
+	signature := mac.Sum(nil)
+	return payload + ":" + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// verifySession 校验会话 cookie 的签名和有效期，返回登录时使用的用户名
+func verifySession(value string) (string, bool) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", false
+	}
+	if time.Now().Unix() > expiresAt {
+		return "", false
+	}
+
+	expected := signSession(mustDecode(parts[0]), expiresAt)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(value)) != 1 {
+		return "", false
+	}
+
+	return mustDecode(parts[0]), true
+}
+
+func mustDecode(s string) string {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// expiredSessionCookie 生成一个立即过期的 cookie，用于 LogoutHandler 清除会话
+func expiredSessionCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+	}
+}
+
+// RequireSession 是保护后台页面和 admin API 的中间件，未登录或会话过期时
+// 重定向到登录页（浏览器导航请求）或者返回 401（API 请求）
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil {
+			denySession(w, r)
+			return
+		}
+		username, ok := verifySession(cookie.Value)
+		if !ok {
+			denySession(w, r)
+			return
+		}
+		r = r.WithContext(withUsername(r.Context(), username))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func denySession(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/metrics" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
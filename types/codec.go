@@ -0,0 +1,55 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "fmt"
+
+// Codec 把内存中的值序列化成字节，或者从字节反序列化回内存值。Segment 只依赖
+// 这个接口，具体用 BSON、JSON 还是 MessagePack 落盘由调用方在写入时选择，
+// 这样同一个数据库里可以混用多种编码而互相之间读写不受影响。
+type Codec interface {
+	// ID 是这个编解码器写入 Segment 头部的 1 字节标识，必须在所有实现里保持唯一
+	ID() byte
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var codecsByID = make(map[byte]Codec)
+
+// RegisterCodec 把 codec 登记到全局注册表，供 CodecByID 按 Segment 头部里的
+// id 查找。每种编解码器都在自己的 init() 里调用它，所以只要 import 了对应的包，
+// 读路径就能认出用它写入的记录。
+func RegisterCodec(codec Codec) {
+	codecsByID[codec.ID()] = codec
+}
+
+// CodecByID 按 Segment 头部里保存的 1 字节 id 查找编解码器
+func CodecByID(id byte) (Codec, error) {
+	codec, ok := codecsByID[id]
+	if !ok {
+		return nil, fmt.Errorf("types: unknown codec id %d", id)
+	}
+	return codec, nil
+}
+
+// Payload 返回 v 交给 Codec.Marshal 时应该用的值。大多数类型可以直接反射编码，
+// 但像 Float 这样把数据放进未导出字段做原子存储的类型，需要实现下面这个接口，
+// 换成一个字段全部导出的中间结构体
+func Payload(v any) any {
+	if p, ok := v.(interface{ CodecPayload() any }); ok {
+		return p.CodecPayload()
+	}
+	return v
+}
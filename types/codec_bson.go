@@ -0,0 +1,40 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "gopkg.in/mgo.v2/bson"
+
+// CodecBSON 是 BSON 编解码器的头部 id，也是历史上日志里唯一出现过的值，
+// 所以 NewSegment 在调用方没有显式指定 codec 时用它兜底
+const CodecBSON byte = 0
+
+type bsonCodec struct{}
+
+func (bsonCodec) ID() byte { return CodecBSON }
+
+func (bsonCodec) Marshal(v any) ([]byte, error) {
+	return bson.Marshal(v)
+}
+
+func (bsonCodec) Unmarshal(data []byte, v any) error {
+	return bson.Unmarshal(data, v)
+}
+
+// BSON 是默认编解码器，保证老版本只写 BSON 时代的日志不需要任何迁移就能继续读出来
+var BSON Codec = bsonCodec{}
+
+func init() {
+	RegisterCodec(BSON)
+}
@@ -0,0 +1,39 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "encoding/json"
+
+// CodecJSON 是 JSON 编解码器的头部 id
+const CodecJSON byte = 1
+
+type jsonCodec struct{}
+
+func (jsonCodec) ID() byte { return CodecJSON }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// JSON 落盘的是人类可读的文本，方便不愿意链接 BSON/MessagePack 库的外部工具直接查看 Value
+var JSON Codec = jsonCodec{}
+
+func init() {
+	RegisterCodec(JSON)
+}
@@ -0,0 +1,39 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "github.com/vmihailenco/msgpack/v5"
+
+// CodecMsgPack 是 MessagePack 编解码器的头部 id
+const CodecMsgPack byte = 2
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ID() byte { return CodecMsgPack }
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// MsgPack 比 JSON 更紧凑，适合对落盘体积敏感、又不想继续依赖 mgo/bson 的场景
+var MsgPack Codec = msgpackCodec{}
+
+func init() {
+	RegisterCodec(MsgPack)
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "testing"
+
+func TestCodecByID(t *testing.T) {
+	for _, codec := range []Codec{BSON, JSON, MsgPack} {
+		got, err := CodecByID(codec.ID())
+		if err != nil {
+			t.Fatalf("CodecByID(%d) error = %v", codec.ID(), err)
+		}
+		if got.ID() != codec.ID() {
+			t.Fatalf("CodecByID(%d) = %d, want %d", codec.ID(), got.ID(), codec.ID())
+		}
+	}
+}
+
+func TestCodecByIDUnknown(t *testing.T) {
+	if _, err := CodecByID(0xFF); err == nil {
+		t.Fatalf("CodecByID(0xFF) error = nil, want unknown codec error")
+	}
+}
+
+func TestCodecMarshalUnmarshalRoundTrip(t *testing.T) {
+	for name, codec := range map[string]Codec{"bson": BSON, "json": JSON, "msgpack": MsgPack} {
+		num := Number{Value: 7}
+		data, err := codec.Marshal(num)
+		if err != nil {
+			t.Fatalf("%s: Marshal() error = %v", name, err)
+		}
+		var got Number
+		if err := codec.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: Unmarshal() error = %v", name, err)
+		}
+		if got.Value != num.Value {
+			t.Fatalf("%s: round trip mismatch: got %+v, want %+v", name, got, num)
+		}
+	}
+}
+
+func TestFloatPayloadRoundTripAcrossCodecs(t *testing.T) {
+	f := NewFloat(3.5)
+	f.TTL = 10
+
+	for name, codec := range map[string]Codec{"bson": BSON, "json": JSON, "msgpack": MsgPack} {
+		data, err := codec.Marshal(f.CodecPayload())
+		if err != nil {
+			t.Fatalf("%s: Marshal() error = %v", name, err)
+		}
+		got, err := FloatFromCodec(codec, data)
+		if err != nil {
+			t.Fatalf("%s: FloatFromCodec() error = %v", name, err)
+		}
+		if got.Get() != f.Get() || got.TTL != f.TTL {
+			t.Fatalf("%s: round trip mismatch: got %+v, want %+v", name, got, f)
+		}
+	}
+}
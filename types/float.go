@@ -0,0 +1,104 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"math"
+	"sync/atomic"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Float 结构体，表示带有浮点数值的类型，底层用 bits 存放 math.Float64bits 编码后的值，
+// 借助 atomic.LoadUint64/StoreUint64 实现无锁的原子读写
+type Float struct {
+	bits uint64 `bson:"-"`
+	TTL  uint64 `json:"ttl,omitempty"`
+}
+
+func NewFloat(f float64) *Float {
+	return &Float{bits: math.Float64bits(f)}
+}
+
+// floatPayload 是 Float 在磁盘上的表示；bits 是原子存储用的内部编码，不能被任何
+// Codec 直接反射填充，所以序列化和反序列化都要经过这个带导出字段的中间结构体
+type floatPayload struct {
+	Value float64 `bson:"number" json:"number"`
+	TTL   uint64  `bson:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+// ToBSON 将 Float 序列化为 BSON
+func (f *Float) ToBSON() ([]byte, error) {
+	return bson.Marshal(floatPayload{Value: f.Get(), TTL: f.TTL})
+}
+
+// CodecPayload 把 Float 换成可以交给任意 Codec.Marshal 的中间结构体，实现
+// NewSegment 接受的 payloadProvider 约定
+func (f *Float) CodecPayload() any {
+	return floatPayload{Value: f.Get(), TTL: f.TTL}
+}
+
+// FloatFromCodec 用指定的 codec 从字节还原一个 Float，配合 Segment.ToFloat 使用；
+// Segment 把写入时用的 codec id 存在头部里，读出来之后必须用同一种 codec 解码
+func FloatFromCodec(codec Codec, data []byte) (*Float, error) {
+	var payload floatPayload
+	if err := codec.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	f := NewFloat(payload.Value)
+	f.TTL = payload.TTL
+	return f, nil
+}
+
+// FloatFromBSON 从 BSON 字节还原一个 Float，兼容老版本只写 BSON 时代的日志
+func FloatFromBSON(data []byte) (*Float, error) {
+	return FloatFromCodec(BSON, data)
+}
+
+// Get 以原子方式获取当前值
+func (f *Float) Get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&f.bits))
+}
+
+// Set 以原子方式设置当前值
+func (f *Float) Set(value float64) {
+	atomic.StoreUint64(&f.bits, math.Float64bits(value))
+}
+
+// CompareAndSwap 仅当当前值等于 old 时，才原子地设置为 new
+func (f *Float) CompareAndSwap(old, new float64) bool {
+	return atomic.CompareAndSwapUint64(&f.bits, math.Float64bits(old), math.Float64bits(new))
+}
+
+// Add 以 CAS 循环的方式原子地增加 delta，返回增加之后的值
+func (f *Float) Add(delta float64) float64 {
+	for {
+		old := f.Get()
+		next := old + delta
+		if f.CompareAndSwap(old, next) {
+			return next
+		}
+	}
+}
+
+// Increment 自增（+1）
+func (f *Float) Increment() float64 {
+	return f.Add(1)
+}
+
+// Decrement 自减（-1）
+func (f *Float) Decrement() float64 {
+	return f.Add(-1)
+}
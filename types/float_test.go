@@ -0,0 +1,58 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import "testing"
+
+func TestFloatAdd(t *testing.T) {
+	f := NewFloat(1.5)
+	if got := f.Add(2.25); got != 3.75 {
+		t.Fatalf("Add() = %v, want 3.75", got)
+	}
+	if got := f.Get(); got != 3.75 {
+		t.Fatalf("Get() = %v, want 3.75", got)
+	}
+}
+
+func TestFloatCompareAndSwap(t *testing.T) {
+	f := NewFloat(1)
+	if !f.CompareAndSwap(1, 2) {
+		t.Fatalf("CompareAndSwap(1, 2) = false, want true")
+	}
+	if f.CompareAndSwap(1, 3) {
+		t.Fatalf("CompareAndSwap(1, 3) = true, want false after value changed")
+	}
+	if got := f.Get(); got != 2 {
+		t.Fatalf("Get() = %v, want 2", got)
+	}
+}
+
+func TestFloatFromBSONRoundTrip(t *testing.T) {
+	f := NewFloat(42.5)
+	f.TTL = 60
+
+	data, err := f.ToBSON()
+	if err != nil {
+		t.Fatalf("ToBSON() error = %v", err)
+	}
+
+	got, err := FloatFromBSON(data)
+	if err != nil {
+		t.Fatalf("FloatFromBSON() error = %v", err)
+	}
+	if got.Get() != f.Get() || got.TTL != f.TTL {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", got, f)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/auula/momentdb/transformer"
+	"github.com/auula/momentdb/types"
+)
+
+// MigrateResult 汇总一次 codec 迁移的结果
+type MigrateResult struct {
+	// Migrated 是被重新编码的记录数，即 codec 等于 from 的非墓碑记录
+	Migrated int
+	// Skipped 是原样拷贝过去的记录数：墓碑，或者本来就不是 from 写入的记录
+	Skipped int
+}
+
+// MigrateCodec 把 src 日志里所有用 from 编码的记录改用 to 重新编码，写到 dst；不是
+// from 写入的记录（包括墓碑）原样拷贝。src 侧走 Decode，本来就会校验 CRC32，
+// 任何已经损坏的记录都会在读出阶段直接报错；dst 侧走 Encode，重新计算 CRC32，
+// 所以迁移结果不可能带着损坏的数据静默落盘。
+func MigrateCodec(src, dst string, from, to types.Codec) (*MigrateResult, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	result := &MigrateResult{}
+
+	for {
+		seg, err := Decode(in)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("decode source segment: %w", err)
+		}
+
+		if seg.IsTombstone() || seg.Codec != from.ID() {
+			result.Skipped++
+		} else {
+			if err := reencode(seg, from, to); err != nil {
+				return result, fmt.Errorf("migrate key %q: %w", seg.Key, err)
+			}
+			result.Migrated++
+		}
+
+		if _, err := seg.Encode(out); err != nil {
+			return result, fmt.Errorf("write migrated segment: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// reencode 把 seg.Value 从 from 解码回它本来的 Go 类型，再用 to 重新编码，然后更新
+// seg 上受影响的字段。必须按 Kind 解码成具体类型而不是 map[string]any 这样的通用结构，
+// 否则像 encoding/json 把所有数字都解成 float64 这样的行为会在多次迁移之间悄悄丢失
+// Number.Value 的整数精度。覆盖所有 Kind 是为了不让迁移工具在碰到 Set/ZSet/List/Text/
+// Table 记录时直接失败——这些类型解码进的目标和 Segment 的 ToX() 相同，但解码前先做 transformer.Decode
+func reencode(seg *Segment, from, to types.Codec) error {
+	plain, err := transformer.Decode(seg.Value)
+	if err != nil {
+		return fmt.Errorf("transformer decode: %w", err)
+	}
+
+	var payload any
+	switch seg.Type {
+	case Set:
+		var set types.Set
+		if err := from.Unmarshal(plain, &set.Set); err != nil {
+			return fmt.Errorf("decode with source codec: %w", err)
+		}
+		payload = types.Payload(set)
+	case ZSet:
+		var zset types.ZSet
+		if err := from.Unmarshal(plain, &zset.ZSet); err != nil {
+			return fmt.Errorf("decode with source codec: %w", err)
+		}
+		payload = types.Payload(zset)
+	case List:
+		var list types.List
+		if err := from.Unmarshal(plain, &list); err != nil {
+			return fmt.Errorf("decode with source codec: %w", err)
+		}
+		payload = types.Payload(list)
+	case Text:
+		var text types.Text
+		if err := from.Unmarshal(plain, &text); err != nil {
+			return fmt.Errorf("decode with source codec: %w", err)
+		}
+		payload = types.Payload(text)
+	case Table:
+		var table types.Table
+		if err := from.Unmarshal(plain, &table); err != nil {
+			return fmt.Errorf("decode with source codec: %w", err)
+		}
+		payload = types.Payload(table)
+	case Number:
+		var number types.Number
+		if err := from.Unmarshal(plain, &number); err != nil {
+			return fmt.Errorf("decode with source codec: %w", err)
+		}
+		payload = number
+	case Float:
+		f, err := types.FloatFromCodec(from, plain)
+		if err != nil {
+			return fmt.Errorf("decode with source codec: %w", err)
+		}
+		payload = f.CodecPayload()
+	default:
+		return fmt.Errorf("migration not supported for kind %d", seg.Type)
+	}
+
+	encoded, err := to.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode with target codec: %w", err)
+	}
+
+	reencoded, err := transformer.Encode(encoded)
+	if err != nil {
+		return fmt.Errorf("transformer encode: %w", err)
+	}
+
+	seg.Codec = to.ID()
+	seg.Value = reencoded
+	seg.ValueSize = uint32(len(reencoded))
+	return nil
+}
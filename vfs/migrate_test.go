@@ -0,0 +1,104 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/auula/momentdb/types"
+)
+
+func TestMigrateCodecRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	dst := filepath.Join(dir, "dst.log")
+
+	seg, err := NewSegment("counter", types.NewNumber(42), 0, types.BSON)
+	if err != nil {
+		t.Fatalf("NewSegment() error = %v", err)
+	}
+
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatalf("Create(src) error = %v", err)
+	}
+	if _, err := seg.Encode(f); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close(src) error = %v", err)
+	}
+
+	result, err := MigrateCodec(src, dst, types.BSON, types.JSON)
+	if err != nil {
+		t.Fatalf("MigrateCodec() error = %v", err)
+	}
+	if result.Migrated != 1 || result.Skipped != 0 {
+		t.Fatalf("result = %+v, want Migrated=1 Skipped=0", result)
+	}
+
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatalf("Open(dst) error = %v", err)
+	}
+	defer out.Close()
+
+	got, err := Decode(out)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Codec != types.JSON.ID() {
+		t.Fatalf("got.Codec = %d, want %d", got.Codec, types.JSON.ID())
+	}
+	number, err := got.ToNumber()
+	if err != nil {
+		t.Fatalf("ToNumber() error = %v", err)
+	}
+	if number.Value != 42 {
+		t.Fatalf("number.Value = %d, want 42", number.Value)
+	}
+}
+
+func TestMigrateCodecSkipsOtherCodecs(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	dst := filepath.Join(dir, "dst.log")
+
+	seg, err := NewSegment("counter", types.NewNumber(1), 0, types.JSON)
+	if err != nil {
+		t.Fatalf("NewSegment() error = %v", err)
+	}
+
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatalf("Create(src) error = %v", err)
+	}
+	if _, err := seg.Encode(f); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close(src) error = %v", err)
+	}
+
+	result, err := MigrateCodec(src, dst, types.BSON, types.MsgPack)
+	if err != nil {
+		t.Fatalf("MigrateCodec() error = %v", err)
+	}
+	if result.Migrated != 0 || result.Skipped != 1 {
+		t.Fatalf("result = %+v, want Migrated=0 Skipped=1", result)
+	}
+}
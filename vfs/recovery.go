@@ -0,0 +1,97 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// RecoveryMode 决定扫描日志文件时遇到损坏记录后的行为
+type RecoveryMode int8
+
+const (
+	// Strict 遇到第一条损坏记录就停止扫描并返回 ErrCorruptSegment
+	Strict RecoveryMode = iota
+	// Lenient 在损坏点停止扫描，把之前已经成功解析的记录当作恢复结果；日志文件本身不会被修改
+	Lenient
+)
+
+// IndexEntry 记录一条 Segment 在日志文件中的偏移量和大小，用于重建内存索引。
+// Ref 是这条记录所在日志文件的标识（文件名，即 filepath.Base 的结果），Offset 只在
+// 同一个 Ref 内部才有意义——重放多份独立文件时，离开了 Ref 的 Offset 无法定位任何东西。
+type IndexEntry struct {
+	Ref    string
+	Offset int64
+	Size   uint32
+	Kind   Kind
+}
+
+// RecoveryResult 汇总一次日志扫描的结果
+type RecoveryResult struct {
+	// Index 是通过存活记录（非墓碑、未被后续同名记录覆盖）重建出的内存索引
+	Index map[string]IndexEntry
+	// TornAt 记录第一处无法解析的字节偏移，没有发生截断时为 -1
+	TornAt int64
+}
+
+// RecoverLog 从头扫描一个日志文件，重建内存索引。strict 模式下遇到损坏记录立即
+// 返回 ErrCorruptSegment；lenient 模式下在损坏点停止扫描（不修改文件），把已经成功解析的记录当作恢复结果，
+// 并在 RecoveryResult.TornAt 中报告断点位置，方便运维定位是哪一次写入被打断。
+func RecoverLog(path string, mode RecoveryMode) (*RecoveryResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := &RecoveryResult{
+		Index:  make(map[string]IndexEntry),
+		TornAt: -1,
+	}
+
+	ref := filepath.Base(path)
+	var offset int64
+	for {
+		seg, err := Decode(f)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			result.TornAt = offset
+			if mode == Strict {
+				return result, err
+			}
+			break
+		}
+
+		size := seg.Size()
+		if seg.IsTombstone() {
+			delete(result.Index, string(seg.Key))
+		} else {
+			result.Index[string(seg.Key)] = IndexEntry{
+				Ref:    ref,
+				Offset: offset,
+				Size:   size,
+				Kind:   seg.Type,
+			}
+		}
+		offset += int64(size)
+	}
+
+	return result, nil
+}
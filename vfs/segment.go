@@ -19,10 +19,13 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/auula/wiredb/types"
-	"gopkg.in/mgo.v2/bson"
+	"github.com/auula/momentdb/transformer"
+	"github.com/auula/momentdb/types"
 )
 
+// ErrKeyNotFound 表示索引中不存在指定的 key，或者对应的记录已经被逻辑删除
+var ErrKeyNotFound = errors.New("vfs: key not found")
+
 type Kind int8
 
 const (
@@ -32,13 +35,17 @@ const (
 	Text
 	Table
 	Number
+	Float
 	Unknown
 )
 
-// | DEL 1 | KIND 1 | EAT 8 | CAT 8 | KLEN 8 | VLEN 8 | KEY ? | VALUE ? | CRC32 4 |
+// | DEL 1 | KIND 1 | CODEC 1 | EAT 8 | CAT 8 | KLEN 4 | VLEN 4 | KEY ? | VALUE ? | CRC32 4 |
 type Segment struct {
 	Tombstone int8
 	Type      Kind
+	// Codec 是写入 Value 时用的 types.Codec.ID()，读出来之后要用同一个 codec 解码，
+	// 所以一份日志文件里可以混用 BSON/JSON/MessagePack 写入的记录
+	Codec     byte
 	ExpiredAt uint64
 	CreatedAt uint64
 	KeySize   uint32
@@ -51,21 +58,26 @@ type Serializable interface {
 	ToBSON() ([]byte, error)
 }
 
-// NewSegment 使用数据类型初始化并返回对应的 Segment
-func NewSegment(key string, data Serializable, ttl uint64) (*Segment, error) {
+// NewSegment 使用数据类型初始化并返回对应的 Segment，codec 为 nil 时使用 types.BSON，
+// 这样老的调用方不需要跟着改签名就能拿到和之前完全一样的落盘格式
+func NewSegment(key string, data Serializable, ttl uint64, codec types.Codec) (*Segment, error) {
 	kind, err := toKind(data)
 	if err != nil {
 		return nil, fmt.Errorf("unsupported data type: %w", err)
 	}
 
+	if codec == nil {
+		codec = types.BSON
+	}
+
 	timestamp, expiredAt := uint64(time.Now().UnixNano()), uint64(0)
 	if ttl > 0 {
 		expiredAt = uint64(time.Now().Add(time.Second * time.Duration(ttl)).UnixNano())
 	}
 
-	bytes, err := data.ToBSON()
+	bytes, err := codec.Marshal(types.Payload(data))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("codec marshal: %w", err)
 	}
 
 	// 这个是通过 transformer 编码之后的
@@ -77,6 +89,7 @@ func NewSegment(key string, data Serializable, ttl uint64) (*Segment, error) {
 	// 如果类型不匹配，则返回错误
 	return &Segment{
 		Type:      kind,
+		Codec:     codec.ID(),
 		Tombstone: 0,
 		CreatedAt: timestamp,
 		ExpiredAt: expiredAt,
@@ -107,19 +120,27 @@ func (s *Segment) IsTombstone() bool {
 }
 
 func (s *Segment) Size() uint32 {
-	// 计算一整块记录的大小，+4 CRC 校验码占用 4 个字节
-	return 26 + s.KeySize + s.ValueSize + 4
+	// 计算一整块记录的大小，headerSize 是定长头部，+4 CRC 校验码占用 4 个字节
+	return headerSize + s.KeySize + s.ValueSize + 4
+}
+
+// codec 返回写入 s.Value 时用的编解码器，配合所有 ToX() 方法做解码
+func (s *Segment) codec() (types.Codec, error) {
+	return types.CodecByID(s.Codec)
 }
 
 func (s *Segment) ToSet() (*types.Set, error) {
 	if s.Type != Set {
 		return nil, fmt.Errorf("not support conversion to set type")
 	}
-	var set types.Set
-	err := bson.Unmarshal(s.Value, &set.Set)
+	codec, err := s.codec()
 	if err != nil {
 		return nil, err
 	}
+	var set types.Set
+	if err := codec.Unmarshal(s.Value, &set.Set); err != nil {
+		return nil, err
+	}
 	return &set, nil
 }
 
@@ -127,11 +148,14 @@ func (s *Segment) ToZSet() (*types.ZSet, error) {
 	if s.Type != ZSet {
 		return nil, fmt.Errorf("not support conversion to zset type")
 	}
-	var zset types.ZSet
-	err := bson.Unmarshal(s.Value, &zset.ZSet)
+	codec, err := s.codec()
 	if err != nil {
 		return nil, err
 	}
+	var zset types.ZSet
+	if err := codec.Unmarshal(s.Value, &zset.ZSet); err != nil {
+		return nil, err
+	}
 	return &zset, nil
 }
 
@@ -139,11 +163,14 @@ func (s *Segment) ToText() (*types.Text, error) {
 	if s.Type != Text {
 		return nil, fmt.Errorf("not support conversion to text type")
 	}
-	var text types.Text
-	err := bson.Unmarshal(s.Value, &text)
+	codec, err := s.codec()
 	if err != nil {
 		return nil, err
 	}
+	var text types.Text
+	if err := codec.Unmarshal(s.Value, &text); err != nil {
+		return nil, err
+	}
 	return &text, nil
 }
 
@@ -151,11 +178,14 @@ func (s *Segment) ToList() (*types.List, error) {
 	if s.Type != List {
 		return nil, fmt.Errorf("not support conversion to list type")
 	}
-	var list types.List
-	err := bson.Unmarshal(s.Value, &list)
+	codec, err := s.codec()
 	if err != nil {
 		return nil, err
 	}
+	var list types.List
+	if err := codec.Unmarshal(s.Value, &list); err != nil {
+		return nil, err
+	}
 	return &list, nil
 }
 
@@ -163,11 +193,14 @@ func (s *Segment) ToTable() (*types.Table, error) {
 	if s.Type != Table {
 		return nil, fmt.Errorf("not support conversion to table type")
 	}
-	var table types.Table
-	err := bson.Unmarshal(s.Value, &table)
+	codec, err := s.codec()
 	if err != nil {
 		return nil, err
 	}
+	var table types.Table
+	if err := codec.Unmarshal(s.Value, &table); err != nil {
+		return nil, err
+	}
 	return &table, nil
 }
 
@@ -175,14 +208,28 @@ func (s *Segment) ToNumber() (*types.Number, error) {
 	if s.Type != Number {
 		return nil, fmt.Errorf("not support conversion to number type")
 	}
-	var number types.Number
-	err := bson.Unmarshal(s.Value, &number)
+	codec, err := s.codec()
 	if err != nil {
 		return nil, err
 	}
+	var number types.Number
+	if err := codec.Unmarshal(s.Value, &number); err != nil {
+		return nil, err
+	}
 	return &number, nil
 }
 
+func (s *Segment) ToFloat() (*types.Float, error) {
+	if s.Type != Float {
+		return nil, fmt.Errorf("not support conversion to float type")
+	}
+	codec, err := s.codec()
+	if err != nil {
+		return nil, err
+	}
+	return types.FloatFromCodec(codec, s.Value)
+}
+
 func (s *Segment) TTL() int64 {
 	now := uint64(time.Now().UnixNano())
 	if s.ExpiredAt > 0 && s.ExpiredAt > now {
@@ -191,23 +238,26 @@ func (s *Segment) TTL() int64 {
 	return -1
 }
 
-// 将类型映射为 Kind 的辅助函数
+// 将类型映射为 Kind 的辅助函数。net/resp 里 types.NewSet()/NewZSet()/NewList()/NewTable()
+// 和 Segment.ToSet()/ToZSet()/ToList()/ToText()/ToTable() 一律返回指针，所以这几个
+// Kind 必须同时匹配值类型和指针类型，否则类型开关落不到对应的 case，NewSegment 会把
+// 这些指针都当成未知类型拒绝掉
 func toKind(data Serializable) (Kind, error) {
 	switch data.(type) {
-	case types.Set:
+	case types.Set, *types.Set:
 		return Set, nil
-	case types.ZSet:
+	case types.ZSet, *types.ZSet:
 		return ZSet, nil
-	case types.List:
+	case types.List, *types.List:
 		return List, nil
-	case types.Text:
+	case types.Text, *types.Text:
 		return Text, nil
-	case types.Table:
+	case types.Table, *types.Table:
 		return Table, nil
-	case types.Number:
-		return Number, nil
-	case *types.Number:
+	case types.Number, *types.Number:
 		return Number, nil
+	case *types.Float:
+		return Float, nil
 	default:
 		return Unknown, errors.New("unknown data type")
 	}
@@ -0,0 +1,52 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"testing"
+
+	"github.com/auula/momentdb/types"
+)
+
+// TestNewSegmentAcceptsPointerKinds 证明 net/resp 里每条命令实际传给 NewSegment 的指针
+// 类型（types.NewSet()/NewZSet()/NewList()/NewTable() 和 Segment.ToX() 都返回指针）都能
+// 被 toKind 正确识别，而不是落到 default 分支被当成未知类型拒绝。
+func TestNewSegmentAcceptsPointerKinds(t *testing.T) {
+	cases := []struct {
+		name string
+		data Serializable
+		kind Kind
+	}{
+		{"Set", types.NewSet(), Set},
+		{"ZSet", types.NewZSet(), ZSet},
+		{"List", types.NewList(), List},
+		{"Text", types.NewText("hello"), Text},
+		{"Table", types.NewTable(), Table},
+		{"Number", types.NewNumber(0), Number},
+		{"Float", types.NewFloat(0), Float},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			seg, err := NewSegment("k", tc.data, 0, nil)
+			if err != nil {
+				t.Fatalf("NewSegment() error = %v", err)
+			}
+			if seg.Type != tc.kind {
+				t.Fatalf("seg.Type = %v, want %v", seg.Type, tc.kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SegmentRef 标识远端存储上的一份已经封存的日志文件，LSN 是它写入时的日志序号，
+// 恢复和复制都按照 LSN 顺序重放，保证多节点上重建出的数据是一致的。
+type SegmentRef struct {
+	ID  string
+	LSN uint64
+}
+
+// SnapshotBackend 是远端快照/复制目标的最小契约，core 引擎只依赖这一个接口，
+// 具体的云厂商实现放在各自的 backend/ 子包里，用编译标签隔离出去，不会拖累 core 的依赖体积。
+type SnapshotBackend interface {
+	// PutSegment 把一份已经封存的日志文件流式上传到远端，id 通常就是本地文件名
+	PutSegment(ctx context.Context, id string, r io.Reader) error
+	// GetSegment 按 id 取回一份日志文件，调用方负责关闭返回的 ReadCloser
+	GetSegment(ctx context.Context, id string) (io.ReadCloser, error)
+	// ListSince 返回 LSN 严格大于 lsn 的全部日志文件引用，按 LSN 升序排列
+	ListSince(ctx context.Context, lsn uint64) ([]SegmentRef, error)
+	// Lock 获取单写者锁，防止多个进程同时向同一个远端命名空间写入
+	Lock(ctx context.Context) error
+	// Unlock 释放 Lock 获取到的单写者锁
+	Unlock(ctx context.Context) error
+}
+
+// RotatedLog 描述一份刚刚被 core 引擎封存、可以安全上传的日志文件
+type RotatedLog struct {
+	ID   string
+	LSN  uint64
+	Path string
+	Open func() (io.ReadCloser, error)
+}
+
+// Snapshotter 在后台把封存的日志文件流式上传到 SnapshotBackend，日志文件通过 Rotated
+// 通道递交给它，上传失败时保留在通道之外由调用方决定重试策略（Snapshotter 本身不重试，
+// 避免和 core 引擎的写入路径产生耦合）。
+type Snapshotter struct {
+	backend SnapshotBackend
+	rotated <-chan RotatedLog
+	errs    chan<- error
+}
+
+// NewSnapshotter 创建一个还未开始运行的 Snapshotter
+func NewSnapshotter(backend SnapshotBackend, rotated <-chan RotatedLog, errs chan<- error) *Snapshotter {
+	return &Snapshotter{backend: backend, rotated: rotated, errs: errs}
+}
+
+// Run 先获取 backend 的单写者锁，防止另一个进程同时往同一个远端命名空间上传，
+// 然后持续消费 rotated 通道直到 ctx 被取消，每上传完一份日志（失败时把错误发到 errs）才会去读取下一份，
+// 保持上传顺序和封存顺序一致。返回前总会释放锁。
+func (s *Snapshotter) Run(ctx context.Context) error {
+	if err := s.backend.Lock(ctx); err != nil {
+		return fmt.Errorf("lock snapshot backend: %w", err)
+	}
+	defer s.backend.Unlock(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case log, ok := <-s.rotated:
+			if !ok {
+				return nil
+			}
+			if err := s.upload(ctx, log); err != nil {
+				s.errs <- err
+			}
+		}
+	}
+}
+
+func (s *Snapshotter) upload(ctx context.Context, log RotatedLog) error {
+	r, err := log.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.backend.PutSegment(ctx, log.ID, r)
+}
+
+// Restore 先获取 backend 的单写者锁，防止恢复过程中另一个进程同时写入同一个远端
+// 命名空间，然后依次拉取 LSN 大于 since 的所有日志文件，把每一条通过 CRC 校验的
+// 记录重新编码进本地的 dst 日志文件，同时重建出内存索引，让 dst 成为一份可以直接
+// 交给 RecoverLog 使用的本地日志。任何一份远端日志损坏都会中止恢复，因为远端快照
+// 本来就应该是发送前已经封存、通过 CRC 校验的完整文件。
+func Restore(ctx context.Context, backend SnapshotBackend, since uint64, dst string) (*RecoveryResult, error) {
+	if err := backend.Lock(ctx); err != nil {
+		return nil, fmt.Errorf("lock snapshot backend: %w", err)
+	}
+	defer backend.Unlock(ctx)
+
+	refs, err := backend.ListSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+
+	result := &RecoveryResult{Index: make(map[string]IndexEntry), TornAt: -1}
+
+	// offset 是 dst 里的写入位置，所有 ref 都重新编码进同一个文件，所以它可以
+	// 在整个循环期间共用，而不会像按远端文件各自计数那样变得毫无意义。
+	// 索引里的 Ref 因此也指向 dst，和 RecoverLog(dst) 重建出的索引一致。
+	dstRef := filepath.Base(dst)
+	var offset int64
+	for _, ref := range refs {
+		if err := replaySegment(ctx, backend, ref, out, dstRef, result, &offset); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func replaySegment(ctx context.Context, backend SnapshotBackend, ref SegmentRef, out io.Writer, dstRef string, result *RecoveryResult, offset *int64) error {
+	r, err := backend.GetSegment(ctx, ref.ID)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for {
+		seg, err := Decode(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		n, err := seg.Encode(out)
+		if err != nil {
+			return fmt.Errorf("write restored segment: %w", err)
+		}
+
+		if seg.IsTombstone() {
+			delete(result.Index, string(seg.Key))
+		} else {
+			result.Index[string(seg.Key)] = IndexEntry{Ref: dstRef, Offset: *offset, Size: seg.Size(), Kind: seg.Type}
+		}
+		*offset += int64(n)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Leon Ding <ding@ibyte.me> https://wiredb.github.io
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//     http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// headerSize 是 CRC32 之前定长部分的字节数：DEL 1 + KIND 1 + CODEC 1 + EAT 8 + CAT 8 + KLEN 4 + VLEN 4
+const headerSize = 27
+
+// crcSize 是尾部 CRC32 字段的字节数
+const crcSize = 4
+
+// maxBodySize 是单条记录 KEY+VALUE 长度之和的上限（512 MiB）。KLEN/VLEN 来自还没有
+// 校验过 CRC32 的头部，一旦头部被破坏，这两个字段可以是任意值；不设上限的话，
+// Decode 会在算出 CRC32 不匹配之前就先按照这个垃圾长度去分配内存
+const maxBodySize = 512 << 20
+
+// ErrCorruptSegment 表示读到的字节流未能通过 CRC32 校验，日志文件很可能被截断或位翻转
+var ErrCorruptSegment = fmt.Errorf("vfs: corrupt segment, crc32 mismatch")
+
+// Encode 按照 | DEL 1 | KIND 1 | CODEC 1 | EAT 8 | CAT 8 | KLEN 4 | VLEN 4 | KEY ? | VALUE ? | CRC32 4 |
+// 的布局把 Segment 写入 w，返回实际写入的字节数
+func (s *Segment) Encode(w io.Writer) (int, error) {
+	buf := make([]byte, headerSize+len(s.Key)+len(s.Value))
+
+	buf[0] = byte(s.Tombstone)
+	buf[1] = byte(s.Type)
+	buf[2] = s.Codec
+	binary.BigEndian.PutUint64(buf[3:11], s.ExpiredAt)
+	binary.BigEndian.PutUint64(buf[11:19], s.CreatedAt)
+	binary.BigEndian.PutUint32(buf[19:23], s.KeySize)
+	binary.BigEndian.PutUint32(buf[23:27], s.ValueSize)
+	copy(buf[headerSize:], s.Key)
+	copy(buf[headerSize+len(s.Key):], s.Value)
+
+	checksum := crc32.ChecksumIEEE(buf)
+
+	n, err := w.Write(buf)
+	if err != nil {
+		return n, err
+	}
+
+	var crcBuf [crcSize]byte
+	binary.BigEndian.PutUint32(crcBuf[:], checksum)
+	m, err := w.Write(crcBuf[:])
+	return n + m, err
+}
+
+// Decode 从 r 中读取一条完整的 Segment 记录并校验其 CRC32，校验失败时返回
+// 包装了 ErrCorruptSegment 的错误，调用方可以用 errors.Is 判断
+func Decode(r io.Reader) (*Segment, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		// 读到了部分头部说明最后一条记录的写入被打断，同样算作损坏；
+		// 一个字节都没读到的 io.EOF 才是日志末尾的正常结束
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: %v", ErrCorruptSegment, err)
+		}
+		return nil, err
+	}
+
+	keySize := binary.BigEndian.Uint32(header[19:23])
+	valueSize := binary.BigEndian.Uint32(header[23:27])
+
+	// 加总用 uint64，避免两个 uint32 相加溢出回绕，绕过下面的上限检查
+	bodySize := uint64(keySize) + uint64(valueSize)
+	if bodySize > maxBodySize {
+		return nil, fmt.Errorf("%w: declared body size %d exceeds %d byte limit", ErrCorruptSegment, bodySize, uint64(maxBodySize))
+	}
+
+	body := make([]byte, bodySize)
+	if _, err := io.ReadFull(r, body); err != nil {
+		// 头部已经声明了这条记录还有多少字节的 body，读到这里说明流提前结束了，
+		// 这和 CRC32 不匹配一样，都是"记录被截断/损坏"，而不是日志末尾的正常 EOF
+		return nil, fmt.Errorf("%w: %v", ErrCorruptSegment, err)
+	}
+
+	var crcBuf [crcSize]byte
+	if _, err := io.ReadFull(r, crcBuf[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptSegment, err)
+	}
+
+	frame := make([]byte, 0, headerSize+len(body))
+	frame = append(frame, header...)
+	frame = append(frame, body...)
+
+	want := binary.BigEndian.Uint32(crcBuf[:])
+	got := crc32.ChecksumIEEE(frame)
+	if got != want {
+		return nil, fmt.Errorf("%w: want %d, got %d", ErrCorruptSegment, want, got)
+	}
+
+	return &Segment{
+		Tombstone: int8(header[0]),
+		Type:      Kind(header[1]),
+		Codec:     header[2],
+		ExpiredAt: binary.BigEndian.Uint64(header[3:11]),
+		CreatedAt: binary.BigEndian.Uint64(header[11:19]),
+		KeySize:   keySize,
+		ValueSize: valueSize,
+		Key:       body[:keySize],
+		Value:     body[keySize:],
+	}, nil
+}